@@ -0,0 +1,108 @@
+// Package notifications dispatches alerts from the monitoring loop to
+// configured channels (Slack, Discord, email, generic webhooks).
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// logger is the structured logger used throughout the notifications package,
+// replacing the previous ad-hoc fmt.Printf calls.
+var logger = slog.Default()
+
+// ChannelType identifies a notification destination and, indirectly, which
+// default template and payload Format it uses.
+type ChannelType string
+
+const (
+	ChannelSlack   ChannelType = "slack"
+	ChannelDiscord ChannelType = "discord"
+	ChannelEmail   ChannelType = "email"
+	ChannelWebhook ChannelType = "webhook"
+)
+
+// Channel is a single configured notification destination.
+type Channel struct {
+	Type ChannelType
+	Name string
+	URL  string
+
+	// Template overrides the channel type's default template when non-empty.
+	Template string
+}
+
+// NotificationSender dispatches notifications to every configured channel.
+type NotificationSender struct {
+	Channels []Channel
+	client   *http.Client
+}
+
+// NewNotificationSender builds a sender for the given channels.
+func NewNotificationSender(channels []Channel) *NotificationSender {
+	return &NotificationSender{Channels: channels, client: http.DefaultClient}
+}
+
+// SendNotifications sends message unchanged to every channel; no template is
+// applied, though the Content-Type header still follows the channel type's
+// Format. Prefer SendMetricSnapshot
+// when a full metric snapshot is available, so channels can render their own
+// richer (Markdown/HTML/JSON) payload.
+func (s *NotificationSender) SendNotifications(message string) {
+	for _, ch := range s.Channels {
+		if err := s.dispatch(ch, message); err != nil {
+			logger.Error("failed to send notification", "channel", ch.Name, "type", ch.Type, "error", err)
+		}
+	}
+}
+
+// SendMetricSnapshot renders snap through each channel's registered template
+// (its own override, or the type's default) and dispatches the result.
+func (s *NotificationSender) SendMetricSnapshot(snap MetricSnapshot) {
+	for _, ch := range s.Channels {
+		body, err := Render(ch.Type, ch.Template, snap)
+		if err != nil {
+			logger.Error("failed to render notification template", "channel", ch.Name, "type", ch.Type, "error", err)
+			continue
+		}
+		if err := s.dispatch(ch, body); err != nil {
+			logger.Error("failed to send notification", "channel", ch.Name, "type", ch.Type, "error", err)
+		}
+	}
+}
+
+func (s *NotificationSender) dispatch(ch Channel, body string) error {
+	if ch.URL == "" {
+		return fmt.Errorf("channel %s has no destination URL configured", ch.Name)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, ch.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentTypeFor(formatFor(ch.Type)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func contentTypeFor(format Format) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatHTML:
+		return "text/html"
+	default:
+		return "text/plain"
+	}
+}
@@ -0,0 +1,43 @@
+package notifications
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// previewRequest is the body for the template preview endpoint: the channel
+// the template is destined for, and the (possibly unsaved) template text to
+// try out.
+type previewRequest struct {
+	Channel  ChannelType `json:"channel"`
+	Template string      `json:"template"`
+}
+
+type previewResponse struct {
+	Body string `json:"body"`
+}
+
+// PreviewHandler renders a user-supplied template against a fake metric
+// snapshot so it can be validated before it's saved as a channel override.
+// Mount it behind the settings API, e.g. POST /api/notifications/preview.
+func PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req previewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	body, err := RenderPreview(req.Channel, req.Template)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(previewResponse{Body: body})
+}
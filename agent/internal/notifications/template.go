@@ -0,0 +1,150 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"text/template"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Format is the payload shape a rendered template produces, so the sender
+// knows which Content-Type to label the request body with for a given channel.
+type Format string
+
+const (
+	FormatPlain    Format = "plain"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatJSON     Format = "json"
+)
+
+// MetricSnapshot is the full set of values a notification template can
+// reference for a single server at the moment a notification fires.
+type MetricSnapshot struct {
+	Name      string
+	Status    string
+	CPU       float64
+	RAM       float64
+	Disk      float64
+	GPU       float64
+	Temp      float64
+	Uptime    string
+	LoadAvg   [3]float64
+	Hostname  string
+	Timestamp time.Time
+}
+
+// templateFuncs exposes humanize helpers to every template so channels can
+// render byte counts and relative times without duplicating formatting
+// logic.
+var templateFuncs = template.FuncMap{
+	"humanizeBytes": func(bytes float64) string { return humanize.Bytes(uint64(bytes)) },
+	"humanizeTime":  func(t time.Time) string { return humanize.Time(t) },
+	"json":          jsonEscape,
+}
+
+// jsonEscape marshals v as JSON so templates that build a JSON payload (e.g.
+// the webhook channel) can interpolate arbitrary server-reported strings
+// without producing invalid JSON when a name or status contains a quote,
+// backslash, or newline. text/template itself does no escaping, so any
+// field rendered with raw {{.Foo}} inside a JSON template is unsafe.
+func jsonEscape(v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// formatFor returns the payload Format a channel type renders by default.
+func formatFor(channel ChannelType) Format {
+	switch channel {
+	case ChannelSlack, ChannelDiscord:
+		return FormatMarkdown
+	case ChannelEmail:
+		return FormatHTML
+	case ChannelWebhook:
+		return FormatJSON
+	default:
+		return FormatPlain
+	}
+}
+
+// defaultTemplates is the built-in template library, one per channel type.
+// Channels can override theirs via Channel.Template.
+var defaultTemplates = map[ChannelType]string{
+	ChannelSlack: "*{{.Name}}* is now *{{.Status}}*\n" +
+		"> CPU: {{printf \"%.1f\" .CPU}}% · RAM: {{printf \"%.1f\" .RAM}}% · Disk: {{printf \"%.1f\" .Disk}}% · Temp: {{printf \"%.1f\" .Temp}}°C\n" +
+		"> Uptime: {{.Uptime}} · Load: {{printf \"%.2f/%.2f/%.2f\" (index .LoadAvg 0) (index .LoadAvg 1) (index .LoadAvg 2)}}\n" +
+		"> {{humanizeTime .Timestamp}}",
+
+	ChannelDiscord: "**{{.Name}}** is now **{{.Status}}**\n" +
+		"CPU: {{printf \"%.1f\" .CPU}}% | RAM: {{printf \"%.1f\" .RAM}}% | Disk: {{printf \"%.1f\" .Disk}}% | Temp: {{printf \"%.1f\" .Temp}}°C\n" +
+		"Uptime: {{.Uptime}} ({{humanizeTime .Timestamp}})",
+
+	ChannelEmail: "<h2>{{.Name}} is now {{.Status}}</h2>" +
+		"<ul>" +
+		"<li>CPU: {{printf \"%.1f\" .CPU}}%</li>" +
+		"<li>RAM: {{printf \"%.1f\" .RAM}}%</li>" +
+		"<li>Disk: {{printf \"%.1f\" .Disk}}%</li>" +
+		"<li>GPU: {{printf \"%.1f\" .GPU}}%</li>" +
+		"<li>Temp: {{printf \"%.1f\" .Temp}}&deg;C</li>" +
+		"<li>Uptime: {{.Uptime}}</li>" +
+		"</ul>" +
+		"<p>Reported {{humanizeTime .Timestamp}} ({{.Hostname}})</p>",
+
+	ChannelWebhook: `{"name":{{json .Name}},"status":{{json .Status}},"cpu":{{.CPU}},"ram":{{.RAM}},"disk":{{.Disk}},` +
+		`"gpu":{{.GPU}},"temp":{{.Temp}},"uptime":{{json .Uptime}},"hostname":{{json .Hostname}},"timestamp":{{json .Timestamp}}}`,
+}
+
+// Render executes the template for channel (overrideTemplate if non-empty,
+// otherwise the channel type's default) against snap.
+func Render(channel ChannelType, overrideTemplate string, snap MetricSnapshot) (string, error) {
+	raw := overrideTemplate
+	if raw == "" {
+		var ok bool
+		raw, ok = defaultTemplates[channel]
+		if !ok {
+			return "", fmt.Errorf("no default template registered for channel %q", channel)
+		}
+	}
+
+	tmpl, err := template.New(string(channel)).Funcs(templateFuncs).Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("parse template for channel %q: %w", channel, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, snap); err != nil {
+		return "", fmt.Errorf("render template for channel %q: %w", channel, err)
+	}
+
+	return buf.String(), nil
+}
+
+// PreviewSnapshot is a representative MetricSnapshot used to validate a
+// template before saving it, without needing a real server to poll.
+func PreviewSnapshot() MetricSnapshot {
+	return MetricSnapshot{
+		Name:      "web-01",
+		Status:    "online",
+		CPU:       42.3,
+		RAM:       68.1,
+		Disk:      51.4,
+		GPU:       0,
+		Temp:      58.2,
+		Uptime:    "12d 4:32:10",
+		LoadAvg:   [3]float64{0.42, 0.51, 0.60},
+		Hostname:  "web-01.internal",
+		Timestamp: time.Now(),
+	}
+}
+
+// RenderPreview renders template against a fake metric snapshot so a user
+// can validate it before saving it as a channel's override.
+func RenderPreview(channel ChannelType, template string) (string, error) {
+	return Render(channel, template, PreviewSnapshot())
+}
@@ -0,0 +1,151 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/corecontrol/agent/internal/store"
+)
+
+// defaultMinNotificationInterval is used when a server has no
+// min_notification_interval configured.
+const defaultMinNotificationInterval = 5 * time.Minute
+
+// alertDebouncer applies flap-damping and hysteresis to status and
+// threshold notifications: a configurable number of consecutive failed
+// polls before "offline", a configurable number of consecutive successes
+// before "online", and a per-server cooldown so a flapping or
+// threshold-hovering host can't exceed one alert per interval. Status flap
+// state survives restarts via server_alert_state; threshold cooldowns are
+// best-effort and kept in memory only.
+type alertDebouncer struct {
+	repo *store.Repository
+
+	mu                sync.Mutex
+	states            map[int]store.AlertState
+	thresholdNotified map[string]time.Time
+}
+
+func newAlertDebouncer(repo *store.Repository) *alertDebouncer {
+	return &alertDebouncer{
+		repo:              repo,
+		states:            make(map[int]store.AlertState),
+		thresholdNotified: make(map[string]time.Time),
+	}
+}
+
+var (
+	alertDebouncerOnce sync.Once
+	alertDebouncerInst *alertDebouncer
+)
+
+// getAlertDebouncer returns the process-wide debouncer, creating it on first
+// use. repo is expected to be the same *store.Repository across the
+// process's lifetime.
+func getAlertDebouncer(repo *store.Repository) *alertDebouncer {
+	alertDebouncerOnce.Do(func() {
+		alertDebouncerInst = newAlertDebouncer(repo)
+	})
+	return alertDebouncerInst
+}
+
+// stateFor returns serverID's flap state, loading it from the in-memory
+// cache or the database on first use. knownOnline is the server's last
+// recorded (undebounced) status from the server table; it seeds Online when there's no
+// persisted flap state yet (a new server, or the first poll after this
+// feature shipped), so an already-online host isn't treated as an
+// offline->online transition and doesn't fire a spurious startup alert once
+// upThreshold polls accumulate.
+func (d *alertDebouncer) stateFor(ctx context.Context, serverID int, knownOnline bool) store.AlertState {
+	d.mu.Lock()
+	state, ok := d.states[serverID]
+	d.mu.Unlock()
+	if ok {
+		return state
+	}
+
+	state, err := d.repo.LoadAlertState(ctx, serverID)
+	if err != nil {
+		state = store.AlertState{ServerID: serverID}
+	}
+
+	if state.LastTransitionAt.IsZero() {
+		state.Online = knownOnline
+	}
+
+	d.mu.Lock()
+	d.states[serverID] = state
+	d.mu.Unlock()
+	return state
+}
+
+// ShouldNotifyStatus records one poll result for serverID and reports
+// whether an online/offline notification should fire this poll, given the
+// configured up/down thresholds and cooldown. The server's flap state is
+// updated and persisted regardless of the outcome. knownOnline is the
+// server's last recorded status (from the server table) and is only used to
+// seed state on the very first poll, before any flap state is persisted.
+func (d *alertDebouncer) ShouldNotifyStatus(ctx context.Context, serverID int, online, knownOnline bool, downThreshold, upThreshold int, cooldown time.Duration) bool {
+	state := d.stateFor(ctx, serverID, knownOnline)
+
+	if online {
+		state.ConsecutiveUp++
+		state.ConsecutiveDown = 0
+	} else {
+		state.ConsecutiveDown++
+		state.ConsecutiveUp = 0
+	}
+
+	fire := false
+	if online != state.Online {
+		threshold := downThreshold
+		count := state.ConsecutiveDown
+		if online {
+			threshold = upThreshold
+			count = state.ConsecutiveUp
+		}
+
+		if count >= threshold {
+			if time.Since(state.LastNotifiedAt) >= cooldown {
+				fire = true
+				state.LastNotifiedAt = time.Now()
+			}
+			state.Online = online
+			state.LastTransitionAt = time.Now()
+			state.ConsecutiveUp = 0
+			state.ConsecutiveDown = 0
+		}
+	}
+
+	d.mu.Lock()
+	d.states[serverID] = state
+	d.mu.Unlock()
+
+	if err := d.repo.SaveAlertState(ctx, state); err != nil {
+		logger.Error("failed to persist alert state", "server_id", serverID, "error", err)
+	}
+
+	return fire
+}
+
+// ShouldNotifyThreshold reports whether a CPU/RAM/Disk/Temp threshold breach
+// for serverID/metric should notify, honoring the same per-server cooldown
+// used for status flapping.
+func (d *alertDebouncer) ShouldNotifyThreshold(serverID int, metric string, cooldown time.Duration) bool {
+	key := thresholdKey(serverID, metric)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if last, ok := d.thresholdNotified[key]; ok && time.Since(last) < cooldown {
+		return false
+	}
+	d.thresholdNotified[key] = time.Now()
+	return true
+}
+
+func thresholdKey(serverID int, metric string) string {
+	return fmt.Sprintf("%d:%s", serverID, metric)
+}
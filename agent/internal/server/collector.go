@@ -0,0 +1,55 @@
+package server
+
+import (
+	"context"
+	"net/http"
+)
+
+// Metrics is the common snapshot a Collector produces for a single poll of a
+// host, regardless of which backend gathered it.
+type Metrics struct {
+	Online    bool
+	CPUUsage  float64
+	RAMUsage  float64
+	DiskUsage float64
+	GPUUsage  float64
+	Temp      float64
+	Uptime    string
+	Load1     float64
+	Load5     float64
+	Load15    float64
+	NUsers    int
+}
+
+// Collector fetches a Metrics snapshot for a single host. Implementations are
+// free to reach the host however they like (Glances HTTP API, in-process
+// gopsutil, a Prometheus scrape, SNMP, ...) as long as they respect ctx
+// cancellation and deadlines.
+type Collector interface {
+	Collect(ctx context.Context) (Metrics, error)
+}
+
+// CollectorFactory builds a Collector for one server. client and baseURL are
+// only meaningful to network-backed collectors; in-process collectors (e.g.
+// the "local" gopsutil backend) ignore them.
+type CollectorFactory func(client *http.Client, baseURL, logPrefix string, serverID int) Collector
+
+// collectorFactories maps a server's MonitoringSource column to the backend
+// that knows how to poll it. Register new sources (SNMP, node_exporter, ...)
+// here instead of branching inside MonitorServers.
+var collectorFactories = map[string]CollectorFactory{
+	"glances":    newGlancesCollector,
+	"local":      newLocalCollector,
+	"prometheus": newPrometheusCollector,
+}
+
+// newCollector resolves the Collector for a server's configured monitoring
+// source, falling back to the Glances backend for "" or an unknown value so
+// existing servers keep working after upgrade.
+func newCollector(source string, client *http.Client, baseURL, logPrefix string, serverID int) Collector {
+	factory, ok := collectorFactories[source]
+	if !ok {
+		factory = collectorFactories["glances"]
+	}
+	return factory(client, baseURL, logPrefix, serverID)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/corecontrol/agent/internal/models"
+	"golang.org/x/sync/errgroup"
+)
+
+// glancesCollector polls a Glances /api/4 endpoint over HTTP, issuing one
+// request per metric family. This is the original, pre-gopsutil collection
+// path, now expressed as a Collector implementation.
+type glancesCollector struct {
+	client    *http.Client
+	baseURL   string
+	logPrefix string
+}
+
+func newGlancesCollector(client *http.Client, baseURL, logPrefix string, _ int) Collector {
+	return &glancesCollector{client: client, baseURL: baseURL, logPrefix: logPrefix}
+}
+
+// Collect fetches CPU, memory, disk, GPU, temperature, uptime and load for
+// the host concurrently, since each is an independent Glances endpoint. CPU,
+// memory and disk are load-bearing: any of them failing marks the host
+// offline. GPU, temperature, uptime and load stay best-effort, matching the
+// existing fetch* helpers.
+func (c *glancesCollector) Collect(ctx context.Context) (Metrics, error) {
+	var (
+		cpuOnline, memOnline, diskOnline              bool
+		cpuUsage, ramUsage, diskUsage, gpuUsage, temp float64
+		uptime                                        string
+		load1, load5, load15                          float64
+		nUsers                                        int
+	)
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		cpuOnline, cpuUsage = fetchCPUUsage(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+	g.Go(func() error {
+		memOnline, ramUsage = fetchMemoryUsage(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+	g.Go(func() error {
+		diskOnline, diskUsage = fetchDiskUsage(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+	g.Go(func() error {
+		_, gpuUsage = fetchGPUUsage(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+	g.Go(func() error {
+		_, temp = fetchTemperature(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+	g.Go(func() error {
+		uptime = fetchUptime(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+	g.Go(func() error {
+		load1, load5, load15, nUsers = fetchLoad(gctx, c.client, c.baseURL, c.logPrefix)
+		return nil
+	})
+
+	_ = g.Wait() // the fetch* helpers never return an error; failures are reported via their bool/ok results
+
+	if !cpuOnline {
+		return Metrics{}, fmt.Errorf("%s: cpu endpoint unreachable", c.logPrefix)
+	}
+	if !memOnline {
+		return Metrics{}, fmt.Errorf("%s: mem endpoint unreachable", c.logPrefix)
+	}
+	if !diskOnline {
+		return Metrics{}, fmt.Errorf("%s: fs endpoint unreachable", c.logPrefix)
+	}
+
+	return Metrics{
+		Online:    true,
+		CPUUsage:  cpuUsage,
+		RAMUsage:  ramUsage,
+		DiskUsage: diskUsage,
+		GPUUsage:  gpuUsage,
+		Temp:      temp,
+		Uptime:    uptime,
+		Load1:     load1,
+		Load5:     load5,
+		Load15:    load15,
+		NUsers:    nUsers,
+	}, nil
+}
+
+// fetchLoad fetches the Glances load endpoint. It is best-effort: a failure
+// or partial payload yields zero values rather than marking the host
+// offline, matching the existing GPU/temperature fetch helpers.
+func fetchLoad(ctx context.Context, client *http.Client, baseURL, logPrefix string) (load1, load5, load15 float64, nUsers int) {
+	resp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/load", baseURL))
+	if err != nil {
+		logger.Warn("load request failed", "server", logPrefix, "error", err)
+		return 0, 0, 0, 0
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		logger.Warn("bad load status code", "server", logPrefix, "status", resp.StatusCode)
+		return 0, 0, 0, 0
+	}
+
+	var loadData models.LoadResponse
+	if err := json.NewDecoder(resp.Body).Decode(&loadData); err != nil {
+		logger.Warn("failed to parse load json", "server", logPrefix, "error", err)
+		return 0, 0, 0, 0
+	}
+
+	return loadData.Min1, loadData.Min5, loadData.Min15, loadData.Users
+}
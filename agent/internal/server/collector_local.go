@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/sensors"
+)
+
+// localCollector gathers metrics in-process via gopsutil instead of calling
+// out to a Glances agent. It's selected for servers whose MonitoringSource is
+// "local" - typically the host CoreControl itself runs on, or any machine
+// where we'd rather not run a separate Glances process.
+type localCollector struct {
+	logPrefix string
+}
+
+func newLocalCollector(_ *http.Client, _ string, logPrefix string, _ int) Collector {
+	return &localCollector{logPrefix: logPrefix}
+}
+
+func (c *localCollector) Collect(ctx context.Context) (Metrics, error) {
+	percents, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil {
+		return Metrics{}, err
+	}
+	var cpuUsage float64
+	if len(percents) > 0 {
+		cpuUsage = percents[0]
+	}
+
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	du, err := disk.UsageWithContext(ctx, "/")
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	hostInfo, err := host.InfoWithContext(ctx)
+	if err != nil {
+		return Metrics{}, err
+	}
+	uptime := formatUptime((time.Duration(hostInfo.Uptime) * time.Second).String())
+
+	var temp float64
+	if sensorReadings, err := sensors.TemperaturesWithContext(ctx); err == nil {
+		for _, reading := range sensorReadings {
+			if reading.Temperature > temp {
+				temp = reading.Temperature
+			}
+		}
+	}
+
+	var load1, load5, load15 float64
+	if avg, err := load.AvgWithContext(ctx); err == nil {
+		load1, load5, load15 = avg.Load1, avg.Load5, avg.Load15
+	}
+
+	var gpuUsage float64 // no generic gopsutil GPU reading; left for a future nvidia-smi/DCGM source.
+
+	var nUsers int
+	if users, err := host.UsersWithContext(ctx); err == nil {
+		nUsers = len(users)
+	}
+
+	return Metrics{
+		Online:    true,
+		CPUUsage:  cpuUsage,
+		RAMUsage:  vm.UsedPercent,
+		DiskUsage: du.UsedPercent,
+		GPUUsage:  gpuUsage,
+		Temp:      temp,
+		Uptime:    uptime,
+		Load1:     load1,
+		Load5:     load5,
+		Load15:    load15,
+		NUsers:    nUsers,
+	}, nil
+}
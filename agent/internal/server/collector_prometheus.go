@@ -0,0 +1,197 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// prometheusCollector scrapes a Prometheus text-format /metrics endpoint
+// (node_exporter, cAdvisor, or the agent itself) instead of Glances. CPU
+// usage is a rate over node_cpu_seconds_total, so it keeps the previous
+// sample per server in cpuSampleCache to compute the delta across polls.
+type prometheusCollector struct {
+	client    *http.Client
+	baseURL   string
+	logPrefix string
+	serverID  int
+}
+
+func newPrometheusCollector(client *http.Client, baseURL, logPrefix string, serverID int) Collector {
+	return &prometheusCollector{client: client, baseURL: baseURL, logPrefix: logPrefix, serverID: serverID}
+}
+
+// cpuSample is the per-server state needed to turn the node_cpu_seconds_total
+// counter into a CPU usage percentage.
+type cpuSample struct {
+	idleSeconds  float64
+	totalSeconds float64
+	takenAt      time.Time
+}
+
+var cpuSampleCache = struct {
+	sync.Mutex
+	samples map[int]cpuSample
+}{samples: make(map[int]cpuSample)}
+
+func (c *prometheusCollector) Collect(ctx context.Context) (Metrics, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/metrics", c.baseURL), nil)
+	if err != nil {
+		return Metrics{}, err
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return Metrics{}, fmt.Errorf("%s: metrics request failed: %w", c.logPrefix, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Metrics{}, fmt.Errorf("%s: bad metrics status code: %d", c.logPrefix, resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return Metrics{}, fmt.Errorf("%s: failed to parse metrics: %w", c.logPrefix, err)
+	}
+
+	m := Metrics{Online: true}
+
+	if cpuUsage, ok := c.cpuUsage(families); ok {
+		m.CPUUsage = cpuUsage
+	} else {
+		logger.Debug("node_cpu_seconds_total missing or no prior sample yet, skipping cpu", "server", c.logPrefix)
+	}
+
+	avail := gaugeValue(families, "node_memory_MemAvailable_bytes")
+	total := gaugeValue(families, "node_memory_MemTotal_bytes")
+	if avail != nil && total != nil && *total > 0 {
+		m.RAMUsage = (1 - *avail/ *total) * 100
+	} else {
+		logger.Debug("node_memory_MemAvailable_bytes/MemTotal_bytes missing, skipping ram", "server", c.logPrefix)
+	}
+
+	if diskUsage, ok := c.diskUsage(families); ok {
+		m.DiskUsage = diskUsage
+	} else {
+		logger.Debug("node_filesystem_avail_bytes for / missing, skipping disk", "server", c.logPrefix)
+	}
+
+	if gpu := gaugeValue(families, "DCGM_FI_DEV_GPU_UTIL"); gpu != nil {
+		m.GPUUsage = *gpu
+	}
+
+	if temp := maxGaugeValue(families, "node_hwmon_temp_celsius"); temp != nil {
+		m.Temp = *temp
+	}
+
+	if now := gaugeValue(families, "node_time_seconds"); now != nil {
+		if boot := gaugeValue(families, "node_boot_time_seconds"); boot != nil {
+			m.Uptime = (time.Duration(*now-*boot) * time.Second).String()
+		}
+	}
+
+	return m, nil
+}
+
+// cpuUsage derives a CPU busy percentage from the node_cpu_seconds_total
+// counter by comparing it against the previous poll. The very first poll for
+// a server has nothing to compare against, so it reports ok=false.
+func (c *prometheusCollector) cpuUsage(families map[string]*dto.MetricFamily) (float64, bool) {
+	family, ok := families["node_cpu_seconds_total"]
+	if !ok {
+		return 0, false
+	}
+
+	var idle, total float64
+	for _, metric := range family.GetMetric() {
+		value := metric.GetCounter().GetValue()
+		total += value
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "mode" && label.GetValue() == "idle" {
+				idle += value
+			}
+		}
+	}
+
+	now := cpuSample{idleSeconds: idle, totalSeconds: total, takenAt: time.Now()}
+
+	cpuSampleCache.Lock()
+	prev, hadPrev := cpuSampleCache.samples[c.serverID]
+	cpuSampleCache.samples[c.serverID] = now
+	cpuSampleCache.Unlock()
+
+	if !hadPrev {
+		return 0, false
+	}
+
+	deltaTotal := now.totalSeconds - prev.totalSeconds
+	deltaIdle := now.idleSeconds - prev.idleSeconds
+	if deltaTotal <= 0 {
+		return 0, false
+	}
+
+	return (1 - deltaIdle/deltaTotal) * 100, true
+}
+
+// diskUsage reads node_filesystem_avail_bytes / node_filesystem_size_bytes
+// for the root filesystem ("/").
+func (c *prometheusCollector) diskUsage(families map[string]*dto.MetricFamily) (float64, bool) {
+	avail := filesystemGauge(families, "node_filesystem_avail_bytes", "/")
+	size := filesystemGauge(families, "node_filesystem_size_bytes", "/")
+	if avail == nil || size == nil || *size == 0 {
+		return 0, false
+	}
+	return (1 - *avail/ *size) * 100, true
+}
+
+// filesystemGauge returns the value of a node_filesystem_* gauge for the
+// series whose "mountpoint" label matches mountpoint.
+func filesystemGauge(families map[string]*dto.MetricFamily, name, mountpoint string) *float64 {
+	family, ok := families[name]
+	if !ok {
+		return nil
+	}
+	for _, metric := range family.GetMetric() {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "mountpoint" && label.GetValue() == mountpoint {
+				v := metric.GetGauge().GetValue()
+				return &v
+			}
+		}
+	}
+	return nil
+}
+
+// gaugeValue returns the value of the first series for a single-value gauge
+// metric family, or nil if the family is absent from this scrape.
+func gaugeValue(families map[string]*dto.MetricFamily, name string) *float64 {
+	family, ok := families[name]
+	if !ok || len(family.GetMetric()) == 0 {
+		return nil
+	}
+	v := family.GetMetric()[0].GetGauge().GetValue()
+	return &v
+}
+
+// maxGaugeValue returns the highest value across all series of a gauge
+// family, used for multi-sensor metrics like node_hwmon_temp_celsius.
+func maxGaugeValue(families map[string]*dto.MetricFamily, name string) *float64 {
+	family, ok := families[name]
+	if !ok || len(family.GetMetric()) == 0 {
+		return nil
+	}
+	var max float64
+	for _, metric := range family.GetMetric() {
+		if v := metric.GetGauge().GetValue(); v > max {
+			max = v
+		}
+	}
+	return &max
+}
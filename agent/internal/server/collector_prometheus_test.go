@@ -0,0 +1,119 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+// serveFixture returns an httptest.Server that always serves the recorded
+// node_exporter scrape at path, matching what newPrometheusCollector expects
+// at "<baseURL>/metrics".
+func serveFixture(t *testing.T, path string) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", path, err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+}
+
+// TestPrometheusCollectorCPUDeltaRate checks that CPU usage is derived from
+// the delta between two scrapes of node_cpu_seconds_total rather than a
+// single absolute reading, and that the first poll for a server has nothing
+// to compare against.
+func TestPrometheusCollectorCPUDeltaRate(t *testing.T) {
+	srv := serveFixture(t, "testdata/node_exporter_1.txt")
+	defer srv.Close()
+
+	const serverID = 1001
+	c := newPrometheusCollector(http.DefaultClient, srv.URL, "[test]", serverID)
+
+	first, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("first Collect: %v", err)
+	}
+	if first.CPUUsage != 0 {
+		t.Fatalf("first poll CPUUsage = %v, want 0 (no prior sample)", first.CPUUsage)
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := os.ReadFile("testdata/node_exporter_2.txt")
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		w.Write(body)
+	})
+
+	second, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("second Collect: %v", err)
+	}
+
+	// idle 1000->1100 (+100), total 1250->1410 (+160): busy = (1 - 100/160) * 100 = 37.5
+	const wantCPU = 37.5
+	if second.CPUUsage != wantCPU {
+		t.Fatalf("second poll CPUUsage = %v, want %v", second.CPUUsage, wantCPU)
+	}
+}
+
+// TestPrometheusCollectorFilesystemMountpointSelection checks that disk usage
+// is read from the "/" mountpoint series and not some other filesystem
+// present in the same scrape.
+func TestPrometheusCollectorFilesystemMountpointSelection(t *testing.T) {
+	srv := serveFixture(t, "testdata/node_exporter_1.txt")
+	defer srv.Close()
+
+	c := newPrometheusCollector(http.DefaultClient, srv.URL, "[test]", 1002)
+
+	m, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	// avail 4e10 / size 1e11 for "/": usage = (1 - 0.4) * 100 = 60
+	const wantDisk = 60.0
+	if m.DiskUsage != wantDisk {
+		t.Fatalf("DiskUsage = %v, want %v (should use \"/\", not \"/boot\")", m.DiskUsage, wantDisk)
+	}
+
+	const wantRAM = 75.0
+	if m.RAMUsage != wantRAM {
+		t.Fatalf("RAMUsage = %v, want %v", m.RAMUsage, wantRAM)
+	}
+
+	const wantTemp = 50.0
+	if m.Temp != wantTemp {
+		t.Fatalf("Temp = %v, want %v (max across hwmon sensors)", m.Temp, wantTemp)
+	}
+}
+
+// TestPrometheusCollectorSkipsAbsentMetrics checks that a scrape missing an
+// entire metric family (e.g. no node_exporter filesystem collector enabled)
+// is treated as best-effort: the collector still reports Online and the
+// metrics it does have, rather than failing the whole poll.
+func TestPrometheusCollectorSkipsAbsentMetrics(t *testing.T) {
+	srv := serveFixture(t, "testdata/node_exporter_no_filesystem.txt")
+	defer srv.Close()
+
+	c := newPrometheusCollector(http.DefaultClient, srv.URL, "[test]", 1003)
+
+	m, err := c.Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if !m.Online {
+		t.Fatal("Online = false, want true even with filesystem metrics absent")
+	}
+	if m.DiskUsage != 0 {
+		t.Fatalf("DiskUsage = %v, want 0 when node_filesystem_* is absent", m.DiskUsage)
+	}
+	const wantRAM = 75.0
+	if m.RAMUsage != wantRAM {
+		t.Fatalf("RAMUsage = %v, want %v", m.RAMUsage, wantRAM)
+	}
+}
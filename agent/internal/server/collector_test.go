@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// fakeCollector is a Collector stand-in for tests, so polling logic can be
+// exercised without reaching out over the network.
+type fakeCollector struct {
+	metrics Metrics
+	err     error
+}
+
+func (f *fakeCollector) Collect(ctx context.Context) (Metrics, error) {
+	return f.metrics, f.err
+}
+
+// TestNewCollectorInjectsFake verifies that a Collector implementation can be
+// registered under a monitoring source and resolved by newCollector, so
+// tests of the monitoring loop don't need a real Glances/Prometheus/gopsutil
+// backend behind them.
+func TestNewCollectorInjectsFake(t *testing.T) {
+	const fakeSource = "fake-test"
+	want := Metrics{Online: true, CPUUsage: 42.5, RAMUsage: 10}
+
+	collectorFactories[fakeSource] = func(client *http.Client, baseURL, logPrefix string, serverID int) Collector {
+		return &fakeCollector{metrics: want}
+	}
+	defer delete(collectorFactories, fakeSource)
+
+	got, err := newCollector(fakeSource, nil, "", "[test]", 1).Collect(context.Background())
+	if err != nil {
+		t.Fatalf("Collect returned error: %v", err)
+	}
+	if got != want {
+		t.Fatalf("Collect() = %+v, want %+v", got, want)
+	}
+}
+
+// TestNewCollectorInjectsFakeError verifies an injected Collector's error is
+// surfaced unchanged, matching how a real backend reports an offline host.
+func TestNewCollectorInjectsFakeError(t *testing.T) {
+	const fakeSource = "fake-test-err"
+	wantErr := errors.New("host unreachable")
+
+	collectorFactories[fakeSource] = func(client *http.Client, baseURL, logPrefix string, serverID int) Collector {
+		return &fakeCollector{err: wantErr}
+	}
+	defer delete(collectorFactories, fakeSource)
+
+	_, err := newCollector(fakeSource, nil, "", "[test]", 1).Collect(context.Background())
+	if !errors.Is(err, wantErr) {
+		t.Fatalf("Collect() error = %v, want %v", err, wantErr)
+	}
+}
+
+// TestNewCollectorFallsBackToGlances verifies an unknown monitoring source
+// still resolves to a working Collector instead of a nil one.
+func TestNewCollectorFallsBackToGlances(t *testing.T) {
+	c := newCollector("nonsense", http.DefaultClient, "http://example.invalid", "[test]", 1)
+	if _, ok := c.(*glancesCollector); !ok {
+		t.Fatalf("newCollector(unknown) = %T, want *glancesCollector", c)
+	}
+}
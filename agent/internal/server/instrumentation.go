@@ -0,0 +1,37 @@
+package server
+
+import (
+	"expvar"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"time"
+)
+
+// pollTimeout bounds how long a single host may take to respond before its
+// poll is abandoned for this tick.
+const pollTimeout = 10 * time.Second
+
+var (
+	pollsTotal       = expvar.NewInt("corecontrol.polls_total")
+	pollsFailed      = expvar.NewInt("corecontrol.polls_failed")
+	lastPollDuration = expvar.NewFloat("corecontrol.poll_duration_seconds")
+)
+
+// logger is the structured logger used throughout the monitoring loop,
+// replacing the previous ad-hoc fmt.Printf calls.
+var logger = slog.Default()
+
+// monitorConcurrency returns the number of hosts MonitorServers will poll at
+// once. It defaults to runtime.NumCPU()*4 and can be overridden with
+// CORECONTROL_MONITOR_CONCURRENCY for environments with many more hosts than
+// cores, or fewer file descriptors to spare.
+func monitorConcurrency() int {
+	if v := os.Getenv("CORECONTROL_MONITOR_CONCURRENCY"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return runtime.NumCPU() * 4
+}
@@ -2,7 +2,6 @@ package server
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -13,134 +12,146 @@ import (
 
 	"github.com/corecontrol/agent/internal/models"
 	"github.com/corecontrol/agent/internal/notifications"
+	"github.com/corecontrol/agent/internal/store"
+	"golang.org/x/sync/errgroup"
 )
 
-// notificationState tracks the last known status for each server
-var notificationState = struct {
-	sync.RWMutex
-	lastStatus map[int]bool
-}{
-	lastStatus: make(map[int]bool),
-}
+// MonitorServers checks and updates the status of all servers. All SQL lives
+// behind repo; history rows are collected during the poll and flushed once
+// in a single batch at the end of the tick.
+func MonitorServers(repo *store.Repository, client *http.Client, servers []models.Server, notifSender *notifications.NotificationSender) {
+	ctx := context.Background()
 
-// MonitorServers checks and updates the status of all servers
-func MonitorServers(db *sql.DB, client *http.Client, servers []models.Server, notifSender *notifications.NotificationSender) {
-	var notificationTemplate string
-	err := db.QueryRow("SELECT notification_text_server FROM settings LIMIT 1").Scan(&notificationTemplate)
-	if err != nil || notificationTemplate == "" {
-		notificationTemplate = "The server !name is now !status!"
-	}
+	defaultMonitoringSource, _ := repo.DefaultMonitoringSource(ctx)
+	downThreshold, upThreshold := repo.AlertThresholds(ctx)
+	debouncer := getAlertDebouncer(repo)
+
+	var historyMu sync.Mutex
+	var history []store.HistoryEntry
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(monitorConcurrency())
 
 	for _, server := range servers {
+		server := server
 		if !server.Monitoring || !server.MonitoringURL.Valid {
 			continue
 		}
 
-		logPrefix := fmt.Sprintf("[Server %s]", server.Name)
-		fmt.Printf("%s Checking...\n", logPrefix)
+		g.Go(func() error {
+			entry := pollServer(gctx, repo, client, server, defaultMonitoringSource, notifSender, debouncer, downThreshold, upThreshold)
+			historyMu.Lock()
+			history = append(history, entry)
+			historyMu.Unlock()
+			return nil
+		})
+	}
 
-		baseURL := strings.TrimSuffix(server.MonitoringURL.String, "/")
-		var cpuUsage, ramUsage, diskUsage, gpuUsage, temp float64
-		var online = true
-		var uptimeStr string
+	_ = g.Wait()
 
-		// Get CPU usage
-		online, cpuUsage = fetchCPUUsage(client, baseURL, logPrefix)
-		if !online {
-			updateServerStatus(db, server.ID, false, 0, 0, 0, 0, 0, "")
-			if shouldSendNotification(server.ID, online) {
-				sendStatusChangeNotification(server, online, notificationTemplate, notifSender)
-			}
-			addServerHistoryEntry(db, server.ID, false, 0, 0, 0, 0, 0)
-			continue
-		}
+	if err := repo.BatchInsertHistory(ctx, history); err != nil {
+		logger.Error("failed to flush server history batch", "error", err)
+	}
+}
 
-		// Get uptime if server is online
-		uptimeStr = fetchUptime(client, baseURL, logPrefix)
+// pollServer collects and persists metrics for a single host, enforcing a
+// hard per-poll deadline so one hung target can't eat the whole tick. It
+// returns the history row for this poll so the caller can batch it with the
+// rest of the tick.
+func pollServer(ctx context.Context, repo *store.Repository, client *http.Client, server models.Server, defaultMonitoringSource string, notifSender *notifications.NotificationSender, debouncer *alertDebouncer, downThreshold, upThreshold int) store.HistoryEntry {
+	start := time.Now()
+	pollsTotal.Add(1)
+	defer func() { lastPollDuration.Set(time.Since(start).Seconds()) }()
+
+	logPrefix := fmt.Sprintf("[Server %s]", server.Name)
+	logger.Info("checking server", "server", server.Name, "id", server.ID)
+
+	cooldown := defaultMinNotificationInterval
+	if server.MinNotificationInterval.Valid {
+		cooldown = time.Duration(server.MinNotificationInterval.Int64) * time.Second
+	}
 
-		// Get Memory usage
-		memOnline, memUsage := fetchMemoryUsage(client, baseURL, logPrefix)
-		if !memOnline {
-			online = false
-			updateServerStatus(db, server.ID, false, 0, 0, 0, 0, 0, "")
-			if shouldSendNotification(server.ID, online) {
-				sendStatusChangeNotification(server, online, notificationTemplate, notifSender)
-			}
-			addServerHistoryEntry(db, server.ID, false, 0, 0, 0, 0, 0)
-			continue
-		}
-		ramUsage = memUsage
-
-		// Get Disk usage
-		diskOnline, diskUsageVal := fetchDiskUsage(client, baseURL, logPrefix)
-		if !diskOnline {
-			online = false
-			updateServerStatus(db, server.ID, false, 0, 0, 0, 0, 0, "")
-			if shouldSendNotification(server.ID, online) {
-				sendStatusChangeNotification(server, online, notificationTemplate, notifSender)
-			}
-			addServerHistoryEntry(db, server.ID, false, 0, 0, 0, 0, 0)
-			continue
-		}
-		diskUsage = diskUsageVal
+	baseURL := strings.TrimSuffix(server.MonitoringURL.String, "/")
 
-		// Get GPU usage
-		_, gpuUsageVal := fetchGPUUsage(client, baseURL, logPrefix)
-		gpuUsage = gpuUsageVal
+	source := server.MonitoringSource.String
+	if source == "" {
+		source = defaultMonitoringSource
+	}
 
-		// Get Temperature
-		_, tempVal := fetchTemperature(client, baseURL, logPrefix)
-		temp = tempVal
+	pollCtx, cancel := context.WithTimeout(ctx, pollTimeout)
+	metrics, err := newCollector(source, client, baseURL, logPrefix, server.ID).Collect(pollCtx)
+	cancel()
 
-		// Check if status changed and send notification if needed
-		if online != server.Online && shouldSendNotification(server.ID, online) {
-			sendStatusChangeNotification(server, online, notificationTemplate, notifSender)
+	if err != nil {
+		pollsFailed.Add(1)
+		logger.Warn("collection failed", "server", server.Name, "id", server.ID, "error", err)
+		if err := repo.UpdateServerStatus(ctx, server.ID, false, store.HistoryMetrics{}); err != nil {
+			logger.Error("failed to update server status", "server", server.Name, "error", err)
 		}
+		if debouncer.ShouldNotifyStatus(ctx, server.ID, false, server.Online, downThreshold, upThreshold, cooldown) {
+			sendStatusChangeNotification(server, false, Metrics{}, notifSender)
+		}
+		return store.HistoryEntry{ServerID: server.ID, Online: false}
+	}
 
-		// Update server status with metrics
-		updateServerStatus(db, server.ID, online, cpuUsage, ramUsage, diskUsage, gpuUsage, temp, uptimeStr)
+	// Flap-damped status-change notification
+	if debouncer.ShouldNotifyStatus(ctx, server.ID, metrics.Online, server.Online, downThreshold, upThreshold, cooldown) {
+		sendStatusChangeNotification(server, metrics.Online, metrics, notifSender)
+	}
 
-		// Add entry to server history
-		addServerHistoryEntry(db, server.ID, online, cpuUsage, ramUsage, diskUsage, gpuUsage, temp)
+	// Debounced threshold notifications for CPU/RAM/Disk/Temp
+	for _, breach := range exceededThresholds(server, metrics) {
+		if debouncer.ShouldNotifyThreshold(server.ID, breach.Metric, cooldown) {
+			sendThresholdNotification(server, breach, metrics, notifSender)
+		}
+	}
 
-		fmt.Printf("%s Updated - CPU: %.2f%%, RAM: %.2f%%, Disk: %.2f%%, GPU: %.2f%%, Temp: %.2f°C, Uptime: %s\n",
-			logPrefix, cpuUsage, ramUsage, diskUsage, gpuUsage, temp, uptimeStr)
+	historyMetrics := store.HistoryMetrics{
+		CPUUsage: metrics.CPUUsage, RAMUsage: metrics.RAMUsage, DiskUsage: metrics.DiskUsage,
+		GPUUsage: metrics.GPUUsage, Temp: metrics.Temp, Uptime: metrics.Uptime,
+		Load1: metrics.Load1, Load5: metrics.Load5, Load15: metrics.Load15, NUsers: metrics.NUsers,
 	}
-}
 
-// shouldSendNotification checks if a notification should be sent based on status change
-func shouldSendNotification(serverID int, online bool) bool {
-	notificationState.Lock()
-	defer notificationState.Unlock()
+	if err := repo.UpdateServerStatus(ctx, server.ID, metrics.Online, historyMetrics); err != nil {
+		logger.Error("failed to update server status", "server", server.Name, "error", err)
+	}
 
-	lastStatus, exists := notificationState.lastStatus[serverID]
+	logger.Info("updated server", "server", server.Name, "id", server.ID,
+		"cpu", metrics.CPUUsage, "ram", metrics.RAMUsage, "disk", metrics.DiskUsage, "gpu", metrics.GPUUsage,
+		"temp", metrics.Temp, "uptime", metrics.Uptime, "load1", metrics.Load1, "load5", metrics.Load5,
+		"load15", metrics.Load15, "users", metrics.NUsers)
 
-	// If this is the first check or status has changed
-	if !exists || lastStatus != online {
-		notificationState.lastStatus[serverID] = online
-		return true
-	}
+	return store.HistoryEntry{ServerID: server.ID, Online: metrics.Online, Metrics: historyMetrics}
+}
 
-	return false
+// httpGet issues a GET request bound to ctx, so a poll deadline actually
+// cancels the in-flight request instead of only bounding how long the caller
+// waits around for *http.Client's own (possibly unset) timeout.
+func httpGet(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return client.Do(req)
 }
 
 // Helper function to fetch CPU usage
-func fetchCPUUsage(client *http.Client, baseURL, logPrefix string) (bool, float64) {
-	cpuResp, err := client.Get(fmt.Sprintf("%s/api/4/cpu", baseURL))
+func fetchCPUUsage(ctx context.Context, client *http.Client, baseURL, logPrefix string) (bool, float64) {
+	cpuResp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/cpu", baseURL))
 	if err != nil {
-		fmt.Printf("%s CPU request failed: %v\n", logPrefix, err)
+		logger.Warn("cpu request failed", "server", logPrefix, "error", err)
 		return false, 0
 	}
 	defer cpuResp.Body.Close()
 
 	if cpuResp.StatusCode != http.StatusOK {
-		fmt.Printf("%s Bad CPU status code: %d\n", logPrefix, cpuResp.StatusCode)
+		logger.Warn("bad cpu status code", "server", logPrefix, "status", cpuResp.StatusCode)
 		return false, 0
 	}
 
 	var cpuData models.CPUResponse
 	if err := json.NewDecoder(cpuResp.Body).Decode(&cpuData); err != nil {
-		fmt.Printf("%s Failed to parse CPU JSON: %v\n", logPrefix, err)
+		logger.Warn("failed to parse cpu json", "server", logPrefix, "error", err)
 		return false, 0
 	}
 
@@ -148,22 +159,22 @@ func fetchCPUUsage(client *http.Client, baseURL, logPrefix string) (bool, float6
 }
 
 // Helper function to fetch memory usage
-func fetchMemoryUsage(client *http.Client, baseURL, logPrefix string) (bool, float64) {
-	memResp, err := client.Get(fmt.Sprintf("%s/api/4/mem", baseURL))
+func fetchMemoryUsage(ctx context.Context, client *http.Client, baseURL, logPrefix string) (bool, float64) {
+	memResp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/mem", baseURL))
 	if err != nil {
-		fmt.Printf("%s Memory request failed: %v\n", logPrefix, err)
+		logger.Warn("memory request failed", "server", logPrefix, "error", err)
 		return false, 0
 	}
 	defer memResp.Body.Close()
 
 	if memResp.StatusCode != http.StatusOK {
-		fmt.Printf("%s Bad memory status code: %d\n", logPrefix, memResp.StatusCode)
+		logger.Warn("bad memory status code", "server", logPrefix, "status", memResp.StatusCode)
 		return false, 0
 	}
 
 	var memData models.MemoryResponse
 	if err := json.NewDecoder(memResp.Body).Decode(&memData); err != nil {
-		fmt.Printf("%s Failed to parse memory JSON: %v\n", logPrefix, err)
+		logger.Warn("failed to parse memory json", "server", logPrefix, "error", err)
 		return false, 0
 	}
 
@@ -171,22 +182,22 @@ func fetchMemoryUsage(client *http.Client, baseURL, logPrefix string) (bool, flo
 }
 
 // Helper function to fetch disk usage
-func fetchDiskUsage(client *http.Client, baseURL, logPrefix string) (bool, float64) {
-	fsResp, err := client.Get(fmt.Sprintf("%s/api/4/fs", baseURL))
+func fetchDiskUsage(ctx context.Context, client *http.Client, baseURL, logPrefix string) (bool, float64) {
+	fsResp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/fs", baseURL))
 	if err != nil {
-		fmt.Printf("%s Filesystem request failed: %v\n", logPrefix, err)
+		logger.Warn("filesystem request failed", "server", logPrefix, "error", err)
 		return false, 0
 	}
 	defer fsResp.Body.Close()
 
 	if fsResp.StatusCode != http.StatusOK {
-		fmt.Printf("%s Bad filesystem status code: %d\n", logPrefix, fsResp.StatusCode)
+		logger.Warn("bad filesystem status code", "server", logPrefix, "status", fsResp.StatusCode)
 		return false, 0
 	}
 
 	var fsData models.FSResponse
 	if err := json.NewDecoder(fsResp.Body).Decode(&fsData); err != nil {
-		fmt.Printf("%s Failed to parse filesystem JSON: %v\n", logPrefix, err)
+		logger.Warn("failed to parse filesystem json", "server", logPrefix, "error", err)
 		return false, 0
 	}
 
@@ -198,13 +209,13 @@ func fetchDiskUsage(client *http.Client, baseURL, logPrefix string) (bool, float
 }
 
 // Helper function to fetch uptime
-func fetchUptime(client *http.Client, baseURL, logPrefix string) string {
-	uptimeResp, err := client.Get(fmt.Sprintf("%s/api/4/uptime", baseURL))
+func fetchUptime(ctx context.Context, client *http.Client, baseURL, logPrefix string) string {
+	uptimeResp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/uptime", baseURL))
 	if err != nil || uptimeResp.StatusCode != http.StatusOK {
 		if err != nil {
-			fmt.Printf("%s Uptime request failed: %v\n", logPrefix, err)
+			logger.Warn("uptime request failed", "server", logPrefix, "error", err)
 		} else {
-			fmt.Printf("%s Bad uptime status code: %d\n", logPrefix, uptimeResp.StatusCode)
+			logger.Warn("bad uptime status code", "server", logPrefix, "status", uptimeResp.StatusCode)
 			uptimeResp.Body.Close()
 		}
 		return ""
@@ -214,7 +225,7 @@ func fetchUptime(client *http.Client, baseURL, logPrefix string) string {
 	// Read the response body as a string first
 	uptimeBytes, err := io.ReadAll(uptimeResp.Body)
 	if err != nil {
-		fmt.Printf("%s Failed to read uptime response: %v\n", logPrefix, err)
+		logger.Warn("failed to read uptime response", "server", logPrefix, "error", err)
 		return ""
 	}
 
@@ -229,27 +240,27 @@ func fetchUptime(client *http.Client, baseURL, logPrefix string) string {
 		uptimeStr = formatUptime(uptimeStr)
 	}
 
-	fmt.Printf("%s Uptime: %s (formatted: %s)\n", logPrefix, string(uptimeBytes), uptimeStr)
+	logger.Debug("uptime fetched", "server", logPrefix, "raw", string(uptimeBytes), "formatted", uptimeStr)
 	return uptimeStr
 }
 
 // Helper function to fetch GPU usage
-func fetchGPUUsage(client *http.Client, baseURL, logPrefix string) (bool, float64) {
-	gpuResp, err := client.Get(fmt.Sprintf("%s/api/4/gpu", baseURL))
+func fetchGPUUsage(ctx context.Context, client *http.Client, baseURL, logPrefix string) (bool, float64) {
+	gpuResp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/gpu", baseURL))
 	if err != nil {
-		fmt.Printf("%s GPU request failed: %v\n", logPrefix, err)
+		logger.Warn("gpu request failed", "server", logPrefix, "error", err)
 		return true, 0 // Return true to indicate server is still online
 	}
 	defer gpuResp.Body.Close()
 
 	if gpuResp.StatusCode != http.StatusOK {
-		fmt.Printf("%s Bad GPU status code: %d\n", logPrefix, gpuResp.StatusCode)
+		logger.Warn("bad gpu status code", "server", logPrefix, "status", gpuResp.StatusCode)
 		return true, 0 // Return true to indicate server is still online
 	}
 
 	var gpuData models.GPUResponse
 	if err := json.NewDecoder(gpuResp.Body).Decode(&gpuData); err != nil {
-		fmt.Printf("%s Failed to parse GPU JSON: %v\n", logPrefix, err)
+		logger.Warn("failed to parse gpu json", "server", logPrefix, "error", err)
 		return true, 0 // Return true to indicate server is still online
 	}
 
@@ -257,22 +268,22 @@ func fetchGPUUsage(client *http.Client, baseURL, logPrefix string) (bool, float6
 }
 
 // Helper function to fetch temperature
-func fetchTemperature(client *http.Client, baseURL, logPrefix string) (bool, float64) {
-	tempResp, err := client.Get(fmt.Sprintf("%s/api/4/sensors/label/value/Composite", baseURL))
+func fetchTemperature(ctx context.Context, client *http.Client, baseURL, logPrefix string) (bool, float64) {
+	tempResp, err := httpGet(ctx, client, fmt.Sprintf("%s/api/4/sensors/label/value/Composite", baseURL))
 	if err != nil {
-		fmt.Printf("%s Temperature request failed: %v\n", logPrefix, err)
+		logger.Warn("temperature request failed", "server", logPrefix, "error", err)
 		return true, 0 // Return true to indicate server is still online
 	}
 	defer tempResp.Body.Close()
 
 	if tempResp.StatusCode != http.StatusOK {
-		fmt.Printf("%s Bad temperature status code: %d\n", logPrefix, tempResp.StatusCode)
+		logger.Warn("bad temperature status code", "server", logPrefix, "status", tempResp.StatusCode)
 		return true, 0 // Return true to indicate server is still online
 	}
 
 	var tempData models.TemperatureResponse
 	if err := json.NewDecoder(tempResp.Body).Decode(&tempData); err != nil {
-		fmt.Printf("%s Failed to parse temperature JSON: %v\n", logPrefix, err)
+		logger.Warn("failed to parse temperature json", "server", logPrefix, "error", err)
 		return true, 0 // Return true to indicate server is still online
 	}
 
@@ -283,49 +294,47 @@ func fetchTemperature(client *http.Client, baseURL, logPrefix string) (bool, flo
 	return true, 0
 }
 
-// Helper function to send notification about status change
-func sendStatusChangeNotification(server models.Server, online bool, template string, notifSender *notifications.NotificationSender) {
+// sendStatusChangeNotification renders the full metric snapshot through each
+// channel's registered template (Slack/Discord Markdown, email HTML, webhook
+// JSON, ...) instead of substituting !name/!status into a flat string.
+func sendStatusChangeNotification(server models.Server, online bool, m Metrics, notifSender *notifications.NotificationSender) {
 	status := "offline"
 	if online {
 		status = "online"
 	}
 
-	message := strings.ReplaceAll(template, "!name", server.Name)
-	message = strings.ReplaceAll(message, "!status", status)
-
-	notifSender.SendNotifications(message)
+	notifSender.SendMetricSnapshot(notifications.MetricSnapshot{
+		Name:      server.Name,
+		Status:    status,
+		CPU:       m.CPUUsage,
+		RAM:       m.RAMUsage,
+		Disk:      m.DiskUsage,
+		GPU:       m.GPUUsage,
+		Temp:      m.Temp,
+		Uptime:    m.Uptime,
+		LoadAvg:   [3]float64{m.Load1, m.Load5, m.Load15},
+		Hostname:  server.Name,
+		Timestamp: time.Now(),
+	})
 }
 
-// Helper function to update server status
-func updateServerStatus(db *sql.DB, serverID int, online bool, cpuUsage, ramUsage, diskUsage, gpuUsage, temp float64, uptime string) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	_, err := db.ExecContext(ctx,
-		`UPDATE server SET online = $1, "cpuUsage" = $2::float8, "ramUsage" = $3::float8, "diskUsage" = $4::float8, "gpuUsage" = $5::float8, "temp" = $6::float8, "uptime" = $7
-		 WHERE id = $8`,
-		online, cpuUsage, ramUsage, diskUsage, gpuUsage, temp, uptime, serverID,
-	)
-	if err != nil {
-		fmt.Printf("Failed to update server status (ID: %d): %v\n", serverID, err)
-	}
-}
-
-// Helper function to add server history entry
-func addServerHistoryEntry(db *sql.DB, serverID int, online bool, cpuUsage, ramUsage, diskUsage, gpuUsage, temp float64) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	_, err := db.ExecContext(ctx,
-		`INSERT INTO server_history(
-			"serverId", online, "cpuUsage", "ramUsage", "diskUsage", "gpuUsage", "temp", "createdAt"
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, now())`,
-		serverID, online, fmt.Sprintf("%.2f", cpuUsage), fmt.Sprintf("%.2f", ramUsage),
-		fmt.Sprintf("%.2f", diskUsage), fmt.Sprintf("%.2f", gpuUsage), fmt.Sprintf("%.2f", temp),
-	)
-	if err != nil {
-		fmt.Printf("Failed to insert server history (ID: %d): %v\n", serverID, err)
-	}
+// sendThresholdNotification renders a breached metric threshold through each
+// channel's registered template, same as sendStatusChangeNotification,
+// instead of dispatching a plain sentence that breaks a webhook's JSON body.
+func sendThresholdNotification(server models.Server, breach metricThreshold, m Metrics, notifSender *notifications.NotificationSender) {
+	notifSender.SendMetricSnapshot(notifications.MetricSnapshot{
+		Name:      server.Name,
+		Status:    thresholdStatus(breach),
+		CPU:       m.CPUUsage,
+		RAM:       m.RAMUsage,
+		Disk:      m.DiskUsage,
+		GPU:       m.GPUUsage,
+		Temp:      m.Temp,
+		Uptime:    m.Uptime,
+		LoadAvg:   [3]float64{m.Load1, m.Load5, m.Load15},
+		Hostname:  server.Name,
+		Timestamp: time.Now(),
+	})
 }
 
 // FormatUptime formats the uptime string to a standard format
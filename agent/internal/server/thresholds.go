@@ -0,0 +1,44 @@
+package server
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/corecontrol/agent/internal/models"
+)
+
+// metricThreshold pairs a metric name with its current value and the
+// user-configured limit it breached.
+type metricThreshold struct {
+	Metric string
+	Value  float64
+	Limit  float64
+}
+
+// exceededThresholds compares a poll's metrics against the per-server
+// CPU/RAM/Disk/Temp limits and returns every one that's currently breached.
+// A server with no configured limit for a metric is never flagged for it.
+func exceededThresholds(server models.Server, m Metrics) []metricThreshold {
+	var breaches []metricThreshold
+
+	check := func(metric string, value float64, limit sql.NullFloat64) {
+		if limit.Valid && value > limit.Float64 {
+			breaches = append(breaches, metricThreshold{Metric: metric, Value: value, Limit: limit.Float64})
+		}
+	}
+
+	check("CPU", m.CPUUsage, server.CPUThreshold)
+	check("RAM", m.RAMUsage, server.RAMThreshold)
+	check("Disk", m.DiskUsage, server.DiskThreshold)
+	check("Temp", m.Temp, server.TempThreshold)
+
+	return breaches
+}
+
+// thresholdStatus renders the short "is now ..." status a breached metric
+// threshold notification reports, used as MetricSnapshot.Status so threshold
+// alerts render through the same per-channel templates as status-change
+// alerts instead of a bypassed plain-text message.
+func thresholdStatus(breach metricThreshold) string {
+	return fmt.Sprintf("%s at %.2f (limit %.2f)", breach.Metric, breach.Value, breach.Limit)
+}
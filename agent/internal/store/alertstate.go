@@ -0,0 +1,73 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// AlertState is the flap-damping state for a single server's online/offline
+// notifications, persisted so restarts don't reset the counters.
+type AlertState struct {
+	ServerID         int
+	Online           bool // last confirmed (debounced) status
+	ConsecutiveUp    int
+	ConsecutiveDown  int
+	LastTransitionAt time.Time
+	LastNotifiedAt   time.Time
+}
+
+// LoadAlertState reads the persisted flap state for a server, returning a
+// zero-value AlertState (never notified, never transitioned) if none exists
+// yet.
+func (r *Repository) LoadAlertState(ctx context.Context, serverID int) (AlertState, error) {
+	state := AlertState{ServerID: serverID}
+
+	err := r.db.QueryRowContext(ctx,
+		`SELECT online, consecutive_up, consecutive_down, last_transition_at, last_notified_at
+		 FROM server_alert_state WHERE server_id = $1`, serverID,
+	).Scan(&state.Online, &state.ConsecutiveUp, &state.ConsecutiveDown, &state.LastTransitionAt, &state.LastNotifiedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return AlertState{ServerID: serverID}, nil
+	}
+	if err != nil {
+		return AlertState{ServerID: serverID}, err
+	}
+	return state, nil
+}
+
+// SaveAlertState upserts a server's flap state.
+func (r *Repository) SaveAlertState(ctx context.Context, s AlertState) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO server_alert_state (server_id, online, consecutive_up, consecutive_down, last_transition_at, last_notified_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (server_id) DO UPDATE SET
+		   online = $2, consecutive_up = $3, consecutive_down = $4, last_transition_at = $5, last_notified_at = $6`,
+		s.ServerID, s.Online, s.ConsecutiveUp, s.ConsecutiveDown, s.LastTransitionAt, s.LastNotifiedAt,
+	)
+	return err
+}
+
+// AlertThresholds reads the admin-configured consecutive-poll thresholds for
+// flipping a server's debounced status, falling back to 3 failed polls
+// before "offline" and 2 successful polls before "online".
+func (r *Repository) AlertThresholds(ctx context.Context) (downThreshold, upThreshold int) {
+	downThreshold, upThreshold = 3, 2
+
+	var down, up sql.NullInt64
+	if err := r.db.QueryRowContext(ctx,
+		"SELECT alert_down_threshold, alert_up_threshold FROM settings LIMIT 1",
+	).Scan(&down, &up); err != nil {
+		return downThreshold, upThreshold
+	}
+
+	if down.Valid {
+		downThreshold = int(down.Int64)
+	}
+	if up.Valid {
+		upThreshold = int(up.Int64)
+	}
+	return downThreshold, upThreshold
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// RetentionThresholds controls when raw server_history rows get rolled up
+// into coarser buckets and eventually dropped. All three are configurable
+// via the settings table so operators can trade history detail for storage.
+type RetentionThresholds struct {
+	RollupToFiveMinAfter time.Duration // rows older than this are bucketed to 5-minute averages
+	RollupToHourlyAfter  time.Duration // rows older than this are bucketed to 1-hour averages
+	RollupToDailyAfter   time.Duration // rows older than this are bucketed to 1-day averages
+}
+
+// defaultRetentionThresholds mirrors the values called out in the request:
+// 1h/24h/30d.
+var defaultRetentionThresholds = RetentionThresholds{
+	RollupToFiveMinAfter: time.Hour,
+	RollupToHourlyAfter:  24 * time.Hour,
+	RollupToDailyAfter:   30 * 24 * time.Hour,
+}
+
+// RetentionThresholds reads the configured rollup thresholds from settings,
+// falling back to the defaults above for any column that's unset.
+func (r *Repository) RetentionThresholds(ctx context.Context) (RetentionThresholds, error) {
+	thresholds := defaultRetentionThresholds
+
+	var fiveMinHours, hourlyHours, dailyHours sql.NullFloat64
+	err := r.db.QueryRowContext(ctx,
+		`SELECT history_rollup_5m_after_hours, history_rollup_1h_after_hours, history_rollup_1d_after_hours FROM settings LIMIT 1`,
+	).Scan(&fiveMinHours, &hourlyHours, &dailyHours)
+	if err != nil {
+		return thresholds, nil
+	}
+
+	if fiveMinHours.Valid {
+		thresholds.RollupToFiveMinAfter = time.Duration(fiveMinHours.Float64 * float64(time.Hour))
+	}
+	if hourlyHours.Valid {
+		thresholds.RollupToHourlyAfter = time.Duration(hourlyHours.Float64 * float64(time.Hour))
+	}
+	if dailyHours.Valid {
+		thresholds.RollupToDailyAfter = time.Duration(dailyHours.Float64 * float64(time.Hour))
+	}
+
+	return thresholds, nil
+}
+
+// StartRetentionRollups launches a background goroutine that periodically
+// aggregates server_history into coarser buckets and deletes the raw rows it
+// rolled up, per the configured RetentionThresholds. It returns immediately;
+// the goroutine stops when ctx is cancelled.
+func (r *Repository) StartRetentionRollups(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := r.runRollup(ctx); err != nil {
+					logger.Error("server_history rollup failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (r *Repository) runRollup(ctx context.Context) error {
+	thresholds, err := r.RetentionThresholds(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := r.rollupBucket(ctx, thresholds.RollupToFiveMinAfter, "5 minutes"); err != nil {
+		return fmt.Errorf("5-minute rollup: %w", err)
+	}
+	if err := r.rollupBucket(ctx, thresholds.RollupToHourlyAfter, "1 hour"); err != nil {
+		return fmt.Errorf("hourly rollup: %w", err)
+	}
+	if err := r.rollupBucket(ctx, thresholds.RollupToDailyAfter, "1 day"); err != nil {
+		return fmt.Errorf("daily rollup: %w", err)
+	}
+	return nil
+}
+
+// rollupBucket averages server_history rows older than age into bucket-wide
+// buckets, writes the averages back as synthetic rows, then deletes the rows
+// it just aggregated. bucket is a Postgres interval literal (e.g. "5
+// minutes").
+//
+// Rows are tagged with bucket_minutes, the width of the bucket they already
+// represent (0 for raw polls). Each stage only consumes rows finer than
+// its own bucket (bucket_minutes < this stage's width) — the 5-minute stage
+// eats raw rows, the hourly stage then eats 5-minute rows (and any raw rows
+// that skipped straight past the 5-minute cutoff), and the daily stage eats
+// hourly rows. Rows a stage has already produced carry its own width, so
+// that stage neither re-aggregates nor deletes them.
+func (r *Repository) rollupBucket(ctx context.Context, age time.Duration, bucket string) error {
+	if age <= 0 {
+		return nil
+	}
+
+	width := bucketMinutes(bucket)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	cutoff := time.Now().Add(-age)
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		INSERT INTO server_history ("serverId", online, "cpuUsage", "ramUsage", "diskUsage", "gpuUsage", "temp", "load1", "load5", "load15", "nUsers", "createdAt", bucket_minutes)
+		SELECT "serverId",
+		       bool_or(online),
+		       avg("cpuUsage"), avg("ramUsage"), avg("diskUsage"), avg("gpuUsage"), avg("temp"),
+		       avg("load1"), avg("load5"), avg("load15"), avg("nUsers")::int,
+		       to_timestamp(floor(extract(epoch from "createdAt") / (%[1]d * 60)) * (%[1]d * 60)),
+		       %[1]d
+		FROM server_history
+		WHERE "createdAt" < $1 AND bucket_minutes < %[1]d
+		GROUP BY "serverId", to_timestamp(floor(extract(epoch from "createdAt") / (%[1]d * 60)) * (%[1]d * 60))
+	`, width), cutoff)
+	if err != nil {
+		return fmt.Errorf("aggregate into %s buckets: %w", bucket, err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM server_history WHERE "createdAt" < $1 AND bucket_minutes < $2`, cutoff, width,
+	); err != nil {
+		return fmt.Errorf("delete rows older than %s: %w", bucket, err)
+	}
+
+	return tx.Commit()
+}
+
+// bucketMinutes converts a Postgres interval literal understood by
+// rollupBucket into the minute-width used for its epoch-based truncation.
+func bucketMinutes(bucket string) int {
+	switch bucket {
+	case "5 minutes":
+		return 5
+	case "1 hour":
+		return 60
+	case "1 day":
+		return 1440
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,129 @@
+// Package store owns the SQL behind server monitoring: status updates,
+// history inserts and the retention rollups that keep server_history from
+// growing unbounded. It exists so the monitoring loop talks to a Repository
+// instead of a raw *sql.DB.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// logger is the structured logger used throughout the store package,
+// replacing the previous ad-hoc fmt.Printf calls.
+var logger = slog.Default()
+
+// Repository owns all history/metric SQL for server monitoring.
+type Repository struct {
+	db *sql.DB
+}
+
+// NewRepository wraps db in a Repository.
+func NewRepository(db *sql.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// HistoryMetrics is the set of metric columns shared by server status
+// updates and server_history rows.
+type HistoryMetrics struct {
+	CPUUsage  float64
+	RAMUsage  float64
+	DiskUsage float64
+	GPUUsage  float64
+	Temp      float64
+	Uptime    string
+	Load1     float64
+	Load5     float64
+	Load15    float64
+	NUsers    int
+}
+
+// HistoryEntry is one server_history row awaiting a batched insert.
+type HistoryEntry struct {
+	ServerID int
+	Online   bool
+	Metrics  HistoryMetrics
+}
+
+// NotificationTemplate reads the configured server status-change message,
+// falling back to the historical default when settings has none.
+func (r *Repository) NotificationTemplate(ctx context.Context) (string, error) {
+	var tmpl string
+	err := r.db.QueryRowContext(ctx, "SELECT notification_text_server FROM settings LIMIT 1").Scan(&tmpl)
+	if err != nil || tmpl == "" {
+		return "The server !name is now !status!", nil
+	}
+	return tmpl, nil
+}
+
+// DefaultMonitoringSource reads the admin-configured default collection
+// backend, falling back to "glances" when settings has none.
+func (r *Repository) DefaultMonitoringSource(ctx context.Context) (string, error) {
+	var source string
+	if err := r.db.QueryRowContext(ctx, "SELECT monitoring_source FROM settings LIMIT 1").Scan(&source); err != nil || source == "" {
+		return "glances", nil
+	}
+	return source, nil
+}
+
+// UpdateServerStatus writes the latest online state and metric snapshot for
+// a single server.
+func (r *Repository) UpdateServerStatus(ctx context.Context, serverID int, online bool, m HistoryMetrics) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE server SET online = $1, "cpuUsage" = $2::float8, "ramUsage" = $3::float8, "diskUsage" = $4::float8,
+		 "gpuUsage" = $5::float8, "temp" = $6::float8, "uptime" = $7, "load1" = $8::float8, "load5" = $9::float8,
+		 "load15" = $10::float8, "nUsers" = $11
+		 WHERE id = $12`,
+		online, m.CPUUsage, m.RAMUsage, m.DiskUsage, m.GPUUsage, m.Temp, m.Uptime, m.Load1, m.Load5, m.Load15, m.NUsers, serverID,
+	)
+	if err != nil {
+		return fmt.Errorf("update server status (id %d): %w", serverID, err)
+	}
+	return nil
+}
+
+// BatchInsertHistory flushes every HistoryEntry collected during a
+// MonitorServers tick in a single round trip via pq.CopyIn, instead of one
+// INSERT per server.
+func (r *Repository) BatchInsertHistory(ctx context.Context, entries []HistoryEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin history batch: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("server_history",
+		"serverId", "online", "cpuUsage", "ramUsage", "diskUsage", "gpuUsage", "temp", "load1", "load5", "load15", "nUsers", "createdAt",
+	))
+	if err != nil {
+		return fmt.Errorf("prepare history copy: %w", err)
+	}
+
+	now := time.Now()
+	for _, e := range entries {
+		m := e.Metrics
+		if _, err := stmt.ExecContext(ctx,
+			e.ServerID, e.Online, m.CPUUsage, m.RAMUsage, m.DiskUsage, m.GPUUsage, m.Temp, m.Load1, m.Load5, m.Load15, m.NUsers, now,
+		); err != nil {
+			return fmt.Errorf("queue history row (server %d): %w", e.ServerID, err)
+		}
+	}
+
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("flush history batch: %w", err)
+	}
+	if err := stmt.Close(); err != nil {
+		return fmt.Errorf("close history copy: %w", err)
+	}
+
+	return tx.Commit()
+}